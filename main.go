@@ -1,18 +1,152 @@
 package main
 
 import (
+	"crypto/sha512"
 	"embed"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 	"usernet/manifest"
+	"usernet/manifest/upload"
 )
 
 //go:embed static
 var staticFiles embed.FS
 var manifestManager *manifest.ManifestManager
+var uploadManager *upload.Manager
+
+// staticOverlayDir is the upload destination (see uploadManager's
+// destinations map in main()) that resumable static-asset uploads land in.
+// It's checked as a fallback whenever an asset isn't in the embedded
+// static FS, so uploads are actually reachable without a redeploy.
+const staticOverlayDir = "static-overlay"
+
+// readStaticAsset resolves a static asset by its path relative to the
+// static/ root, checking the embedded build first and falling back to
+// staticOverlayDir for assets landed there by a resumable upload.
+func readStaticAsset(relPath string) (content []byte, etag string, err error) {
+	if content, err = staticFiles.ReadFile("static/" + relPath); err == nil {
+		return content, quoteETag(relPath), nil
+	}
+	content, overlayErr := os.ReadFile(filepath.Join(staticOverlayDir, relPath))
+	if overlayErr != nil {
+		return nil, "", err
+	}
+	return content, quoteETag(relPath), nil
+}
+
+// staticIntegrity holds the SRI digest for each static asset - embedded or
+// uploaded to staticOverlayDir - keyed by its path relative to the static/
+// root. It's seeded at startup by buildStaticIntegrity and kept current as
+// uploads land via refreshOverlayDigest, so it doubles as the ETag source
+// for readStaticAsset and the published /.well-known/static/integrity.json
+// manifest.
+var (
+	staticIntegrityMu sync.RWMutex
+	staticIntegrity   map[string]string
+)
+
+func quoteETag(relPath string) string {
+	staticIntegrityMu.RLock()
+	defer staticIntegrityMu.RUnlock()
+	return `"` + staticIntegrity[relPath] + `"`
+}
+
+func snapshotStaticIntegrity() map[string]string {
+	staticIntegrityMu.RLock()
+	defer staticIntegrityMu.RUnlock()
+	snapshot := make(map[string]string, len(staticIntegrity))
+	for k, v := range staticIntegrity {
+		snapshot[k] = v
+	}
+	return snapshot
+}
+
+// sriDigest computes a sha384 Subresource Integrity digest for content.
+func sriDigest(content []byte) string {
+	sum := sha512.Sum384(content)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// buildStaticIntegrity computes a sha384 SRI digest for every embedded
+// static asset plus anything already sitting in staticOverlayDir from a
+// previous run's uploads.
+func buildStaticIntegrity() (map[string]string, error) {
+	digests := make(map[string]string)
+	err := fs.WalkDir(staticFiles, "static", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		content, err := staticFiles.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		relPath := strings.TrimPrefix(path, "static/")
+		digests[relPath] = sriDigest(content)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = filepath.WalkDir(staticOverlayDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(staticOverlayDir, path)
+		if err != nil {
+			return err
+		}
+		digests[filepath.ToSlash(relPath)] = sriDigest(content)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return digests, nil
+}
+
+// refreshOverlayDigest recomputes and stores the SRI digest for one
+// overlay asset, so integrity.json and subsequent ETags reflect a
+// resumable upload without waiting for a restart.
+func refreshOverlayDigest(finalPath string) error {
+	relPath, err := filepath.Rel(staticOverlayDir, finalPath)
+	if err != nil {
+		return err
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	content, err := os.ReadFile(finalPath)
+	if err != nil {
+		return err
+	}
+
+	staticIntegrityMu.Lock()
+	staticIntegrity[relPath] = sriDigest(content)
+	staticIntegrityMu.Unlock()
+	return nil
+}
 
 func displaySplash() {
 	fmt.Print(`
@@ -78,21 +212,36 @@ func handleManifestRequest(w http.ResponseWriter, r *http.Request, parts []strin
 
 	// Create request context
 	ctx := &manifest.RequestContext{
-		UserAgent:   r.UserAgent(),
-		AcceptTypes: r.Header["Accept"],
-		Headers:     r.Header,
+		UserAgent:      r.UserAgent(),
+		AcceptTypes:    r.Header["Accept"],
+		AcceptLanguage: r.Header["Accept-Language"],
+		Headers:        r.Header,
 	}
 
+	// Manifest responses vary on these, so let intermediaries cache correctly
+	w.Header().Set("Vary", "Accept, Accept-Language, User-Agent")
+
+	// Let registered enrichers fill in country, device class, auth claims, etc.
+	manifestManager.RunEnrichers(r, ctx)
+
 	// If no specific service is requested, return the api manifest
 	if len(parts) == 0 || parts[0] == "" {
 		fmt.Printf("🏠 Returning _default manifest\n")
-		response, contentType, err := manifestManager.GetResponseForRequest("_default", ctx)
+		response, contentType, signature, err := manifestManager.GetResponseForRequest("_default", ctx)
 		if err != nil {
+			if notAcceptable, ok := err.(*manifest.NotAcceptableError); ok {
+				fmt.Printf("⚠️ No acceptable representation for _default manifest\n")
+				writeNotAcceptable(w, notAcceptable)
+				return
+			}
 			fmt.Printf("❌ Error loading _default manifest: %v\n", err)
 			http.Error(w, "Error loading _default manifest", http.StatusInternalServerError)
 			return
 		}
 		w.Header().Set("Content-Type", contentType)
+		if signature != "" {
+			w.Header().Set("X-Manifest-Signature", signature)
+		}
 		if strResponse, ok := response.(string); ok {
 			w.Write([]byte(strResponse))
 			fmt.Printf("✅ _default manifest served successfully\n")
@@ -106,13 +255,21 @@ func handleManifestRequest(w http.ResponseWriter, r *http.Request, parts []strin
 	// Handle service-specific manifest requests
 	serviceName := parts[0]
 	fmt.Printf("🔧 Service manifest requested: '%s'\n", serviceName)
-	response, contentType, err := manifestManager.GetResponseForRequest(serviceName, ctx)
+	response, contentType, signature, err := manifestManager.GetResponseForRequest(serviceName, ctx)
 	if err != nil {
+		if notAcceptable, ok := err.(*manifest.NotAcceptableError); ok {
+			fmt.Printf("⚠️ No acceptable representation for service manifest '%s'\n", serviceName)
+			writeNotAcceptable(w, notAcceptable)
+			return
+		}
 		fmt.Printf("❌ Error loading service manifest '%s': %v\n", serviceName, err)
 		http.Error(w, "Error loading service manifest", http.StatusInternalServerError)
 		return
 	}
 	w.Header().Set("Content-Type", contentType)
+	if signature != "" {
+		w.Header().Set("X-Manifest-Signature", signature)
+	}
 	if strResponse, ok := response.(string); ok {
 		w.Write([]byte(strResponse))
 		fmt.Printf("✅ Service manifest '%s' served successfully\n", serviceName)
@@ -121,6 +278,17 @@ func handleManifestRequest(w http.ResponseWriter, r *http.Request, parts []strin
 		http.Error(w, "Invalid response type", http.StatusInternalServerError)
 	}
 }
+// writeNotAcceptable responds 406 with the content types the manifest could
+// actually have served, so the client can retry with a workable Accept header.
+func writeNotAcceptable(w http.ResponseWriter, err *manifest.NotAcceptableError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotAcceptable)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":     "not acceptable",
+		"available": err.Available,
+	})
+}
+
 func seed() ([]byte, error) {
 
 	return []byte("seeded"), nil
@@ -130,17 +298,82 @@ func main() {
 	displaySplash()
 	go terminalInterface()
 	manifestManager = manifest.NewManifestManager("manifest")
+	if err := manifestManager.LoadEnrichersFromYAML("manifest/enrichers.yaml"); err != nil {
+		log.Fatalf("failed to load enricher config: %v", err)
+	}
+	if err := manifestManager.SigningKeys().LoadKeysFromDir("manifest/keys"); err != nil {
+		log.Fatalf("failed to load signing keys: %v", err)
+	}
+
+	// Both admin surfaces below write arbitrary manifest/template files to
+	// disk, so they share one Authorizer, configured via ADMIN_TOKEN.
+	var adminAuthorizer upload.Authorizer
+	if token := os.Getenv("ADMIN_TOKEN"); token != "" {
+		authorizer := manifest.BearerTokenAuthorizer{Token: token}
+		manifestManager.SetAuthorizer(authorizer)
+		adminAuthorizer = authorizer
+	} else {
+		log.Println("⚠️  ADMIN_TOKEN not set - /.well-known/manifest/ and /.well-known/upload/ are running with NO AUTHORIZATION")
+	}
+
+	integrity, err := buildStaticIntegrity()
+	if err != nil {
+		log.Fatalf("failed to build static asset integrity manifest: %v", err)
+	}
+	staticIntegrity = integrity
+
+	uploadManager, err = upload.NewManager("manifest/.uploads", map[string]string{
+		"manifest": "manifest",
+		"static":   staticOverlayDir,
+	}, 30*time.Minute)
+	if err != nil {
+		log.Fatalf("failed to start upload manager: %v", err)
+	}
+	uploadManager.Authorizer = adminAuthorizer
+	uploadManager.OnFinalize = func(destName, finalPath string) {
+		switch destName {
+		case "manifest":
+			// A manifest or template file landed directly on disk, bypassing
+			// UpdateManifest's cache invalidation - drop the whole cache so
+			// it (and any compiled templates) get reloaded on next request.
+			manifestManager.InvalidateCache()
+		case "static":
+			// Keep integrity.json and the asset's ETag in sync with what
+			// just landed in the overlay, instead of only covering the
+			// embedded build.
+			if err := refreshOverlayDigest(finalPath); err != nil {
+				fmt.Printf("⚠️ Failed to refresh integrity digest for %q: %v\n", finalPath, err)
+			}
+		}
+	}
+
 	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/static/integrity.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(snapshotStaticIntegrity())
+	})
+	mux.HandleFunc("/.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(manifestManager.SigningKeys().JWKS())
+	})
 	mux.HandleFunc("/.well-known/static/", func(w http.ResponseWriter, r *http.Request) {
 		path := strings.TrimPrefix(r.URL.Path, "/.well-known/static/")
 		fmt.Printf("📁 STATIC FILE REQUEST: %s\n", path)
 
-		content, err := staticFiles.ReadFile("static/" + path)
+		content, etag, err := readStaticAsset(path)
 		if err != nil {
 			fmt.Printf("❌ Static file not found: %s\n", path)
 			http.Error(w, "Not found", http.StatusNotFound)
 			return
 		}
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "public, immutable, max-age=31536000")
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
 		if strings.HasSuffix(path, ".gif") {
 			w.Header().Set("Content-Type", "image/gif")
 		} else if strings.HasSuffix(path, ".html") {
@@ -149,6 +382,8 @@ func main() {
 		w.Write(content)
 		fmt.Printf("✅ Static file served: %s (%d bytes)\n", path, len(content))
 	})
+	mux.Handle("/.well-known/manifest/", http.StripPrefix("/.well-known/manifest", manifestManager.AdminHandler()))
+	mux.Handle("/.well-known/upload/", http.StripPrefix("/.well-known/upload", uploadManager.Handler("/.well-known/upload")))
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		//general request handling
 		parts := strings.Split(r.URL.Path, "/")
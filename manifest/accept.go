@@ -0,0 +1,210 @@
+package manifest
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mediaRange is a single parsed entry from an Accept header, e.g. "text/html;q=0.9".
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// parseAccept parses one or more Accept header values into media ranges sorted
+// by q value (descending), then by specificity: a concrete "type/subtype"
+// outranks "type/*", which outranks the catch-all "*/*" (RFC 7231 ss5.3.2).
+func parseAccept(values []string) []mediaRange {
+	var ranges []mediaRange
+	for _, header := range values {
+		for _, part := range strings.Split(header, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			segments := strings.Split(part, ";")
+			typ, subtype := splitMediaType(strings.TrimSpace(segments[0]))
+			if typ == "" {
+				continue
+			}
+			mr := mediaRange{typ: typ, subtype: subtype, q: 1.0}
+			for _, seg := range segments[1:] {
+				name, value, ok := strings.Cut(strings.TrimSpace(seg), "=")
+				if !ok || strings.TrimSpace(name) != "q" {
+					continue
+				}
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					mr.q = parsed
+				}
+			}
+			ranges = append(ranges, mr)
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+
+	return ranges
+}
+
+func splitMediaType(mediaType string) (string, string) {
+	typ, subtype, ok := strings.Cut(mediaType, "/")
+	if !ok {
+		return "", ""
+	}
+	return strings.TrimSpace(typ), strings.TrimSpace(subtype)
+}
+
+// specificity ranks a media range for tie-breaking between equal q values.
+func (mr mediaRange) specificity() int {
+	switch {
+	case mr.typ != "*" && mr.subtype != "*":
+		return 2
+	case mr.typ != "*":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// isExplicitAccept reports whether the parsed Accept header expressed any
+// preference beyond the universal "*/*" wildcard.
+func isExplicitAccept(ranges []mediaRange) bool {
+	for _, mr := range ranges {
+		if mr.typ != "*" || mr.subtype != "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateContentType picks the best entry in available per the client's
+// Accept header. ok is false when available is empty, or when Accept was
+// explicit (not absent, not "*/*") and none of the available types satisfy
+// it - callers should treat that as a 406 Not Acceptable.
+func negotiateContentType(accept []string, available []string) (string, bool) {
+	if len(available) == 0 {
+		return "", false
+	}
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return available[0], true
+	}
+
+	// Per RFC 7231 ss5.3.2, the most specific range naming a candidate
+	// governs it regardless of q on less specific ranges - so an explicit
+	// "text/html;q=0" must reject text/html even if "*/*;q=0.5" also
+	// matches it with a higher q.
+	var bestCandidate string
+	var best *mediaRange
+	for _, candidate := range available {
+		mr := mostSpecificRange(ranges, candidate)
+		if mr == nil || mr.q <= 0 {
+			continue
+		}
+		if best == nil || mr.q > best.q || (mr.q == best.q && mr.specificity() > best.specificity()) {
+			best = mr
+			bestCandidate = candidate
+		}
+	}
+	if best != nil {
+		return bestCandidate, true
+	}
+
+	if isExplicitAccept(ranges) {
+		return "", false
+	}
+	return available[0], true
+}
+
+// mostSpecificRange returns whichever range in ranges most specifically
+// names contentType (a concrete type/subtype beats type/*, which beats
+// */*), ignoring q, or nil if no range applies to it at all.
+func mostSpecificRange(ranges []mediaRange, contentType string) *mediaRange {
+	typ, subtype := splitMediaType(contentType)
+	var best *mediaRange
+	for i := range ranges {
+		mr := &ranges[i]
+		if (mr.typ == "*" || mr.typ == typ) && (mr.subtype == "*" || mr.subtype == subtype) {
+			if best == nil || mr.specificity() > best.specificity() {
+				best = mr
+			}
+		}
+	}
+	return best
+}
+
+// languageRange is a single parsed entry from an Accept-Language header.
+type languageRange struct {
+	tag string
+	q   float64
+}
+
+func parseAcceptLanguage(values []string) []languageRange {
+	var ranges []languageRange
+	for _, header := range values {
+		for _, part := range strings.Split(header, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			segments := strings.Split(part, ";")
+			tag := strings.TrimSpace(segments[0])
+			if tag == "" {
+				continue
+			}
+			lr := languageRange{tag: tag, q: 1.0}
+			for _, seg := range segments[1:] {
+				name, value, ok := strings.Cut(strings.TrimSpace(seg), "=")
+				if !ok || strings.TrimSpace(name) != "q" {
+					continue
+				}
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					lr.q = parsed
+				}
+			}
+			ranges = append(ranges, lr)
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+	return ranges
+}
+
+// matches reports whether a range (e.g. "en" or "en-US") matches an
+// available tag, allowing a primary-subtag range to match a more specific tag.
+func (lr languageRange) matches(tag string) bool {
+	if lr.q <= 0 {
+		return false
+	}
+	if lr.tag == "*" {
+		return true
+	}
+	if strings.EqualFold(lr.tag, tag) {
+		return true
+	}
+	primary, _, ok := strings.Cut(tag, "-")
+	return ok && strings.EqualFold(lr.tag, primary)
+}
+
+// negotiateLanguage picks the best available language tag for the client's
+// Accept-Language header. Unlike content negotiation, missing a match is not
+// an error: callers fall back to the manifest's default, untranslated response.
+func negotiateLanguage(accept []string, available []string) (string, bool) {
+	if len(available) == 0 {
+		return "", false
+	}
+	for _, lr := range parseAcceptLanguage(accept) {
+		for _, candidate := range available {
+			if lr.matches(candidate) {
+				return candidate, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,44 @@
+package manifest
+
+import "testing"
+
+func TestNegotiateContentTypeExplicitExclusionBeatsWildcard(t *testing.T) {
+	cases := []struct {
+		name      string
+		accept    []string
+		available []string
+		wantType  string
+		wantOK    bool
+	}{
+		{
+			name:      "explicit q=0 rejects despite a higher-q wildcard",
+			accept:    []string{"text/html;q=0, */*;q=0.5"},
+			available: []string{"text/html"},
+			wantType:  "",
+			wantOK:    false,
+		},
+		{
+			name:      "explicit q=0 on one type still allows another via wildcard",
+			accept:    []string{"text/html;q=0, */*;q=0.5"},
+			available: []string{"application/json"},
+			wantType:  "application/json",
+			wantOK:    true,
+		},
+		{
+			name:      "no exclusion falls through to the wildcard",
+			accept:    []string{"*/*;q=0.5"},
+			available: []string{"text/html"},
+			wantType:  "text/html",
+			wantOK:    true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, ok := negotiateContentType(c.accept, c.available)
+			if got != c.wantType || ok != c.wantOK {
+				t.Errorf("negotiateContentType(%v, %v) = (%q, %v), want (%q, %v)", c.accept, c.available, got, ok, c.wantType, c.wantOK)
+			}
+		})
+	}
+}
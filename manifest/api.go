@@ -0,0 +1,309 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Authorizer decides whether an admin API request may proceed. Install a
+// real implementation with SetAuthorizer before exposing AdminHandler
+// publicly; the manager defaults to AllowAll.
+type Authorizer interface {
+	Authorize(r *http.Request) error
+}
+
+// AllowAll is an Authorizer that permits every request.
+type AllowAll struct{}
+
+// Authorize always succeeds.
+func (AllowAll) Authorize(r *http.Request) error { return nil }
+
+// BearerTokenAuthorizer requires an exact "Authorization: Bearer <token>"
+// match against a single shared secret. It satisfies both this package's
+// Authorizer and manifest/upload's - the two admin surfaces that write to
+// disk can share one instance.
+type BearerTokenAuthorizer struct {
+	Token string
+}
+
+// Authorize implements Authorizer.
+func (a BearerTokenAuthorizer) Authorize(r *http.Request) error {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token != a.Token {
+		return fmt.Errorf("missing or invalid bearer token")
+	}
+	return nil
+}
+
+// SetAuthorizer installs the Authorizer consulted before every admin request.
+func (m *ManifestManager) SetAuthorizer(a Authorizer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.authorizer = a
+}
+
+func (m *ManifestManager) authorizerOrDefault() Authorizer {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.authorizer == nil {
+		return AllowAll{}
+	}
+	return m.authorizer
+}
+
+// etagFor computes a strong ETag from a manifest's raw on-disk bytes.
+func etagFor(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// AdminHandler serves the manifest management REST API. It is meant to be
+// mounted with http.StripPrefix so that r.URL.Path is just the service name
+// (or empty, for the listing route):
+//
+//	GET    /            list known services
+//	GET    /<service>    fetch a manifest, with a strong ETag
+//	PUT    /<service>    replace a manifest wholesale
+//	PATCH  /<service>    RFC 7396 JSON Merge Patch, see applyManifestPatch
+//	DELETE /<service>    remove a manifest
+//
+// PUT/PATCH/DELETE honor If-Match/If-None-Match, responding 412 on mismatch.
+func (m *ManifestManager) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := m.authorizerOrDefault().Authorize(r); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		service := strings.Trim(r.URL.Path, "/")
+
+		switch {
+		case r.Method == http.MethodGet && service == "":
+			m.handleListServices(w, r)
+		case r.Method == http.MethodGet:
+			m.handleGetManifest(w, r, service)
+		case r.Method == http.MethodPut && service != "":
+			m.handlePutManifest(w, r, service)
+		case r.Method == http.MethodPatch && service != "":
+			m.handlePatchManifest(w, r, service)
+		case r.Method == http.MethodDelete && service != "":
+			m.handleDeleteManifest(w, r, service)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func (m *ManifestManager) handleListServices(w http.ResponseWriter, r *http.Request) {
+	services, err := m.ListServices()
+	if err != nil {
+		http.Error(w, "failed to list services", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"services": services})
+}
+
+func (m *ManifestManager) handleGetManifest(w http.ResponseWriter, r *http.Request, service string) {
+	data, err := m.readManifestRaw(service)
+	if err != nil {
+		if os.IsNotExist(err) {
+			http.Error(w, "manifest not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to read manifest", http.StatusInternalServerError)
+		return
+	}
+
+	etag := etagFor(data)
+	if r.Header.Get("If-None-Match") == etag {
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (m *ManifestManager) handlePutManifest(w http.ResponseWriter, r *http.Request, service string) {
+	lock := m.lockService(service)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if !m.checkPreconditions(w, r, service) {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	var replacement ServiceManifest
+	if err := json.Unmarshal(body, &replacement); err != nil {
+		http.Error(w, "invalid manifest JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := m.UpdateManifest(service, &replacement); err != nil {
+		http.Error(w, "failed to save manifest", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := m.readManifestRaw(service)
+	if err == nil {
+		w.Header().Set("ETag", etagFor(data))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (m *ManifestManager) handlePatchManifest(w http.ResponseWriter, r *http.Request, service string) {
+	lock := m.lockService(service)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if !m.checkPreconditions(w, r, service) {
+		return
+	}
+
+	current, err := m.readManifestRaw(service)
+	if err != nil {
+		if os.IsNotExist(err) {
+			http.Error(w, "manifest not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to read manifest", http.StatusInternalServerError)
+		return
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := applyManifestPatch(current, r.URL.Query().Get("field"), r.URL.Query().Get("key"), patch)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := m.writeManifestFile(service, updated); err != nil {
+		http.Error(w, "failed to save manifest", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", etagFor(updated))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (m *ManifestManager) handleDeleteManifest(w http.ResponseWriter, r *http.Request, service string) {
+	lock := m.lockService(service)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if !m.checkPreconditions(w, r, service) {
+		return
+	}
+	if err := m.DeleteManifest(service); err != nil {
+		if os.IsNotExist(err) {
+			http.Error(w, "manifest not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to delete manifest", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkPreconditions enforces If-Match/If-None-Match against the manifest's
+// current ETag. It writes the response itself and returns false when the
+// caller should stop.
+func (m *ManifestManager) checkPreconditions(w http.ResponseWriter, r *http.Request, service string) bool {
+	ifMatch := r.Header.Get("If-Match")
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if ifMatch == "" && ifNoneMatch == "" {
+		return true
+	}
+
+	current, err := m.readManifestRaw(service)
+	exists := err == nil
+
+	if ifNoneMatch == "*" && exists {
+		http.Error(w, "manifest already exists", http.StatusPreconditionFailed)
+		return false
+	}
+	if ifNoneMatch != "" && ifNoneMatch != "*" && exists && ifNoneMatch == etagFor(current) {
+		http.Error(w, "precondition failed", http.StatusPreconditionFailed)
+		return false
+	}
+	if ifMatch != "" {
+		if !exists || ifMatch != etagFor(current) {
+			http.Error(w, "precondition failed", http.StatusPreconditionFailed)
+			return false
+		}
+	}
+	return true
+}
+
+// applyManifestPatch applies an RFC 7396 JSON Merge Patch to one field of a
+// manifest: default_response by default, or a single entry within
+// user_agent_cases/country_cases/language_cases when both field and key are
+// given.
+func applyManifestPatch(manifestJSON []byte, field, key string, patch []byte) ([]byte, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(manifestJSON, &raw); err != nil {
+		return nil, fmt.Errorf("invalid manifest on disk: %v", err)
+	}
+	if raw == nil {
+		raw = make(map[string]json.RawMessage)
+	}
+	if field == "" {
+		field = "default_response"
+	}
+
+	switch field {
+	case "default_response":
+		merged, err := applyMergePatch(raw["default_response"], patch)
+		if err != nil {
+			return nil, err
+		}
+		raw["default_response"] = merged
+	case "user_agent_cases", "country_cases", "language_cases":
+		if key == "" {
+			return nil, fmt.Errorf("?key= is required when patching %s", field)
+		}
+		var cases map[string]json.RawMessage
+		if len(raw[field]) > 0 {
+			if err := json.Unmarshal(raw[field], &cases); err != nil {
+				return nil, err
+			}
+		}
+		if cases == nil {
+			cases = make(map[string]json.RawMessage)
+		}
+		merged, err := applyMergePatch(cases[key], patch)
+		if err != nil {
+			return nil, err
+		}
+		cases[key] = merged
+		encoded, err := json.Marshal(cases)
+		if err != nil {
+			return nil, err
+		}
+		raw[field] = encoded
+	default:
+		return nil, fmt.Errorf("unknown field %q", field)
+	}
+
+	return json.MarshalIndent(raw, "", "  ")
+}
@@ -0,0 +1,65 @@
+package manifest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentPutWithSameIfMatchOnlyOneSucceeds guards against a race where
+// two PUTs carrying an identical, valid If-Match both read the same current
+// ETag, both pass checkPreconditions, and both write - instead of the loser
+// getting 412. handlePutManifest must hold a per-service lock across the
+// whole check-then-write sequence to prevent that.
+func TestConcurrentPutWithSameIfMatchOnlyOneSucceeds(t *testing.T) {
+	m := NewManifestManager(t.TempDir())
+	handler := m.AdminHandler()
+
+	put := func(body, ifMatch string) int {
+		req := httptest.NewRequest(http.MethodPut, "/svc", strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := put(`{"default_response":{"v":0}}`, ""); code != http.StatusOK {
+		t.Fatalf("seed PUT status = %d, want 200", code)
+	}
+
+	data, err := m.readManifestRaw("svc")
+	if err != nil {
+		t.Fatalf("readManifestRaw: %v", err)
+	}
+	etag := etagFor(data)
+
+	var wg sync.WaitGroup
+	statuses := make([]int, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			statuses[i] = put(`{"default_response":{"v":1}}`, etag)
+		}(i)
+	}
+	wg.Wait()
+
+	oks, preconditionFailed := 0, 0
+	for _, code := range statuses {
+		switch code {
+		case http.StatusOK:
+			oks++
+		case http.StatusPreconditionFailed:
+			preconditionFailed++
+		default:
+			t.Errorf("unexpected status %d", code)
+		}
+	}
+	if oks != 1 || preconditionFailed != 1 {
+		t.Errorf("statuses = %v, want exactly one 200 and one 412", statuses)
+	}
+}
@@ -0,0 +1,37 @@
+package manifest
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ContextEnricher augments a RequestContext with signal derived from the
+// incoming request - geolocation, device classification, auth claims, and
+// so on. Enrichers run in registration order; a failing enricher is logged
+// and skipped rather than aborting the chain (see RunEnrichers).
+type ContextEnricher interface {
+	Enrich(r *http.Request, ctx *RequestContext) error
+}
+
+// AddEnricher appends an enricher to the chain RunEnrichers walks.
+func (m *ManifestManager) AddEnricher(e ContextEnricher) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enrichers = append(m.enrichers, e)
+}
+
+// RunEnrichers applies every registered enricher to ctx in order. A single
+// bad signal - a corrupt GeoIP db, an unparsable JWT - shouldn't take down
+// manifest serving, so failures are logged and the chain continues.
+func (m *ManifestManager) RunEnrichers(r *http.Request, ctx *RequestContext) {
+	m.mu.RLock()
+	enrichers := make([]ContextEnricher, len(m.enrichers))
+	copy(enrichers, m.enrichers)
+	m.mu.RUnlock()
+
+	for _, e := range enrichers {
+		if err := e.Enrich(r, ctx); err != nil {
+			fmt.Printf("⚠️ context enricher failed: %v\n", err)
+		}
+	}
+}
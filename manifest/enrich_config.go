@@ -0,0 +1,48 @@
+package manifest
+
+import (
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// enricherConfig describes which built-in ContextEnrichers to wire up.
+type enricherConfig struct {
+	GeoIPDatabase string `yaml:"geoip_database"`
+	DeviceClass   bool   `yaml:"device_class"`
+	JWTSecret     string `yaml:"jwt_secret"`
+}
+
+// LoadEnrichersFromYAML reads a small YAML config and registers the
+// requested built-in enrichers on the manager. A missing file isn't an
+// error - it just means no enrichers get configured.
+func (m *ManifestManager) LoadEnrichersFromYAML(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var cfg enricherConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return err
+	}
+
+	if cfg.GeoIPDatabase != "" {
+		geoip, err := NewGeoIPEnricher(cfg.GeoIPDatabase)
+		if err != nil {
+			return err
+		}
+		m.AddEnricher(geoip)
+	}
+	if cfg.DeviceClass {
+		m.AddEnricher(DeviceClassEnricher{})
+	}
+	if cfg.JWTSecret != "" {
+		m.AddEnricher(NewJWTClaimsEnricher([]byte(cfg.JWTSecret)))
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package manifest
+
+import (
+	"net/http"
+	"strings"
+)
+
+// DeviceClassEnricher does a coarse User-Agent classification into "bot",
+// "mobile", or "desktop", driving ServiceManifest.DeviceCases.
+type DeviceClassEnricher struct{}
+
+var botMarkers = []string{"bot", "spider", "crawl", "slurp", "curl", "wget"}
+var mobileMarkers = []string{"mobi", "android", "iphone", "ipod", "ipad"}
+
+// Enrich implements ContextEnricher.
+func (DeviceClassEnricher) Enrich(r *http.Request, ctx *RequestContext) error {
+	ua := strings.ToLower(ctx.UserAgent)
+	if ua == "" {
+		ua = strings.ToLower(r.UserAgent())
+	}
+
+	for _, marker := range botMarkers {
+		if strings.Contains(ua, marker) {
+			ctx.DeviceClass = "bot"
+			return nil
+		}
+	}
+	for _, marker := range mobileMarkers {
+		if strings.Contains(ua, marker) {
+			ctx.DeviceClass = "mobile"
+			return nil
+		}
+	}
+	ctx.DeviceClass = "desktop"
+	return nil
+}
@@ -0,0 +1,76 @@
+package manifest
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// GeoIPEnricher resolves a request's country from a MaxMind
+// GeoLite2-Country/GeoIP2-Country mmdb file, falling back to the
+// CF-IPCountry or X-Country header when the database can't answer -
+// no file configured, a private/unroutable address, or a lookup miss.
+type GeoIPEnricher struct {
+	db *maxminddb.Reader
+}
+
+// NewGeoIPEnricher opens the mmdb file at path. Pass "" to rely solely on
+// the CF-IPCountry/X-Country header fallback.
+func NewGeoIPEnricher(path string) (*GeoIPEnricher, error) {
+	if path == "" {
+		return &GeoIPEnricher{}, nil
+	}
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &GeoIPEnricher{db: db}, nil
+}
+
+// Close releases the underlying mmdb file, if one was opened.
+func (g *GeoIPEnricher) Close() error {
+	if g.db == nil {
+		return nil
+	}
+	return g.db.Close()
+}
+
+// Enrich implements ContextEnricher.
+func (g *GeoIPEnricher) Enrich(r *http.Request, ctx *RequestContext) error {
+	if country := g.lookup(r); country != "" {
+		ctx.Country = country
+		return nil
+	}
+	if country := r.Header.Get("CF-IPCountry"); country != "" {
+		ctx.Country = country
+		return nil
+	}
+	ctx.Country = r.Header.Get("X-Country")
+	return nil
+}
+
+func (g *GeoIPEnricher) lookup(r *http.Request) string {
+	if g.db == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return ""
+	}
+
+	var record struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+	}
+	if err := g.db.Lookup(ip, &record); err != nil {
+		return ""
+	}
+	return record.Country.ISOCode
+}
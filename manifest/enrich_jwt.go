@@ -0,0 +1,106 @@
+package manifest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// JWTClaimsEnricher verifies an HS256-signed bearer token from the
+// Authorization header and copies its claims into ctx.Claims, where
+// templates can reach them via the "claim" function.
+type JWTClaimsEnricher struct {
+	secret []byte
+}
+
+// NewJWTClaimsEnricher builds an enricher that verifies tokens against the
+// given shared HS256 secret.
+func NewJWTClaimsEnricher(secret []byte) *JWTClaimsEnricher {
+	return &JWTClaimsEnricher{secret: secret}
+}
+
+// Enrich implements ContextEnricher.
+func (j *JWTClaimsEnricher) Enrich(r *http.Request, ctx *RequestContext) error {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil // no bearer token presented, nothing to enrich
+	}
+
+	claims, err := verifyHS256(token, j.secret)
+	if err != nil {
+		return fmt.Errorf("jwt claims enrichment: %v", err)
+	}
+	ctx.Claims = claims
+	return nil
+}
+
+func verifyHS256(token string, secret []byte) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid header encoding: %v", err)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("invalid header: %v", err)
+	}
+	if header.Alg != "HS256" {
+		return nil, fmt.Errorf("unsupported alg %q", header.Alg)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	expected := mac.Sum(nil)
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding: %v", err)
+	}
+	if !hmac.Equal(signature, expected) {
+		return nil, fmt.Errorf("signature mismatch")
+	}
+
+	payloadJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload encoding: %v", err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payloadJSON, &claims); err != nil {
+		return nil, fmt.Errorf("invalid payload: %v", err)
+	}
+
+	now := time.Now()
+	if exp, ok := numericDateClaim(claims, "exp"); ok && now.After(exp) {
+		return nil, fmt.Errorf("token expired")
+	}
+	if nbf, ok := numericDateClaim(claims, "nbf"); ok && now.Before(nbf) {
+		return nil, fmt.Errorf("token not yet valid")
+	}
+
+	return claims, nil
+}
+
+// numericDateClaim reads a JWT NumericDate claim (seconds since the Unix
+// epoch, per RFC 7519 §2) and reports whether it was present and well-formed.
+func numericDateClaim(claims map[string]interface{}, name string) (time.Time, bool) {
+	v, ok := claims[name]
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, ok := v.(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
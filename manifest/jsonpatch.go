@@ -0,0 +1,268 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// patchOp is one operation of an RFC 6902 JSON Patch document.
+type patchOp struct {
+	Op    string
+	Path  string
+	From  string
+	Value interface{}
+}
+
+// asJSONPatch reports whether v is a JSON Patch document - a non-empty JSON
+// array of objects each carrying at least "op" and "path" - and decodes it
+// if so.
+func asJSONPatch(v interface{}) ([]patchOp, bool) {
+	items, ok := v.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, false
+	}
+
+	ops := make([]patchOp, 0, len(items))
+	for _, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		op, hasOp := obj["op"].(string)
+		opPath, hasPath := obj["path"].(string)
+		if !hasOp || !hasPath {
+			return nil, false
+		}
+		from, _ := obj["from"].(string)
+		ops = append(ops, patchOp{Op: op, Path: opPath, From: from, Value: obj["value"]})
+	}
+	return ops, true
+}
+
+// applyJSONPatch applies an RFC 6902 JSON Patch document to doc.
+func applyJSONPatch(doc interface{}, ops []patchOp) (interface{}, error) {
+	for _, op := range ops {
+		var err error
+		switch op.Op {
+		case "add":
+			doc, err = pointerAdd(doc, splitPointer(op.Path), op.Value)
+		case "replace":
+			doc, err = pointerReplace(doc, splitPointer(op.Path), op.Value)
+		case "remove":
+			doc, err = pointerRemove(doc, splitPointer(op.Path))
+		case "move":
+			var val interface{}
+			val, doc, err = pointerExtract(doc, splitPointer(op.From))
+			if err == nil {
+				doc, err = pointerAdd(doc, splitPointer(op.Path), val)
+			}
+		case "copy":
+			var val interface{}
+			val, err = pointerGet(doc, splitPointer(op.From))
+			if err == nil {
+				doc, err = pointerAdd(doc, splitPointer(op.Path), val)
+			}
+		case "test":
+			err = pointerTest(doc, splitPointer(op.Path), op.Value)
+		default:
+			err = fmt.Errorf("unsupported op %q", op.Op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("patch op %q %q: %v", op.Op, op.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+// splitPointer splits an RFC 6901 JSON Pointer into unescaped tokens.
+func splitPointer(pointer string) []string {
+	pointer = strings.TrimPrefix(pointer, "/")
+	if pointer == "" {
+		return nil
+	}
+	parts := strings.Split(pointer, "/")
+	for i, p := range parts {
+		p = strings.ReplaceAll(p, "~1", "/")
+		p = strings.ReplaceAll(p, "~0", "~")
+		parts[i] = p
+	}
+	return parts
+}
+
+func arrayIndex(token string, length int) (int, error) {
+	if token == "-" {
+		return length, nil
+	}
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return idx, nil
+}
+
+func pointerGet(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return doc, nil
+	}
+	switch container := doc.(type) {
+	case map[string]interface{}:
+		child, ok := container[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("member %q not found", path[0])
+		}
+		return pointerGet(child, path[1:])
+	case []interface{}:
+		idx, err := arrayIndex(path[0], len(container))
+		if err != nil || idx >= len(container) {
+			return nil, fmt.Errorf("invalid array index %q", path[0])
+		}
+		return pointerGet(container[idx], path[1:])
+	default:
+		return nil, fmt.Errorf("cannot descend into a scalar value")
+	}
+}
+
+func pointerAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	if len(path) == 1 {
+		switch container := doc.(type) {
+		case map[string]interface{}:
+			container[path[0]] = value
+			return container, nil
+		case []interface{}:
+			idx, err := arrayIndex(path[0], len(container))
+			if err != nil || idx > len(container) {
+				return nil, fmt.Errorf("invalid array index %q", path[0])
+			}
+			container = append(container, nil)
+			copy(container[idx+1:], container[idx:])
+			container[idx] = value
+			return container, nil
+		default:
+			return nil, fmt.Errorf("cannot add into a scalar value")
+		}
+	}
+
+	switch container := doc.(type) {
+	case map[string]interface{}:
+		child, ok := container[path[0]]
+		if !ok {
+			child = map[string]interface{}{}
+		}
+		updated, err := pointerAdd(child, path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		container[path[0]] = updated
+		return container, nil
+	case []interface{}:
+		idx, err := arrayIndex(path[0], len(container))
+		if err != nil || idx >= len(container) {
+			return nil, fmt.Errorf("invalid array index %q", path[0])
+		}
+		updated, err := pointerAdd(container[idx], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		container[idx] = updated
+		return container, nil
+	default:
+		return nil, fmt.Errorf("cannot descend into a scalar value")
+	}
+}
+
+func pointerReplace(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	if _, err := pointerGet(doc, path); err != nil {
+		return nil, err
+	}
+	return pointerAdd(doc, path, value)
+}
+
+func pointerRemove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("cannot remove the document root")
+	}
+	if len(path) == 1 {
+		switch container := doc.(type) {
+		case map[string]interface{}:
+			if _, ok := container[path[0]]; !ok {
+				return nil, fmt.Errorf("member %q not found", path[0])
+			}
+			delete(container, path[0])
+			return container, nil
+		case []interface{}:
+			idx, err := arrayIndex(path[0], len(container))
+			if err != nil || idx >= len(container) {
+				return nil, fmt.Errorf("invalid array index %q", path[0])
+			}
+			return append(container[:idx], container[idx+1:]...), nil
+		default:
+			return nil, fmt.Errorf("cannot remove from a scalar value")
+		}
+	}
+
+	switch container := doc.(type) {
+	case map[string]interface{}:
+		child, ok := container[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("member %q not found", path[0])
+		}
+		updated, err := pointerRemove(child, path[1:])
+		if err != nil {
+			return nil, err
+		}
+		container[path[0]] = updated
+		return container, nil
+	case []interface{}:
+		idx, err := arrayIndex(path[0], len(container))
+		if err != nil || idx >= len(container) {
+			return nil, fmt.Errorf("invalid array index %q", path[0])
+		}
+		updated, err := pointerRemove(container[idx], path[1:])
+		if err != nil {
+			return nil, err
+		}
+		container[idx] = updated
+		return container, nil
+	default:
+		return nil, fmt.Errorf("cannot descend into a scalar value")
+	}
+}
+
+func pointerExtract(doc interface{}, path []string) (interface{}, interface{}, error) {
+	val, err := pointerGet(doc, path)
+	if err != nil {
+		return nil, doc, err
+	}
+	newDoc, err := pointerRemove(doc, path)
+	if err != nil {
+		return nil, doc, err
+	}
+	return val, newDoc, nil
+}
+
+func pointerTest(doc interface{}, path []string, expected interface{}) error {
+	actual, err := pointerGet(doc, path)
+	if err != nil {
+		return err
+	}
+	actualJSON, err := json.Marshal(actual)
+	if err != nil {
+		return err
+	}
+	expectedJSON, err := json.Marshal(expected)
+	if err != nil {
+		return err
+	}
+	if string(actualJSON) != string(expectedJSON) {
+		return fmt.Errorf("test failed: value mismatch")
+	}
+	return nil
+}
@@ -23,30 +23,89 @@ type ServiceManifest struct {
 	DefaultResponse json.RawMessage            `json:"default_response"`
 	UserAgentCases  map[string]json.RawMessage `json:"user_agent_cases"`
 	CountryCases    map[string]json.RawMessage `json:"country_cases"`
+	LanguageCases   map[string]json.RawMessage `json:"language_cases,omitempty"`
+	DeviceCases     map[string]json.RawMessage `json:"device_cases,omitempty"`
 	Templates       []ResponseTemplate         `json:"templates,omitempty"`
+	SigningKeyID    string                     `json:"signing_key_id,omitempty"`
 }
 
 // RequestContext holds all the information about an incoming request
 type RequestContext struct {
-	UserAgent     string
-	AcceptTypes   []string
-	Headers       map[string][]string
-	PreferredType string // The content type we'll respond with
-	Country       string // The country code for the request
+	UserAgent      string
+	AcceptTypes    []string
+	AcceptLanguage []string
+	Headers        map[string][]string
+	PreferredType  string // The content type we'll respond with
+	Country        string // The country code for the request
+	Language       string // The negotiated language key from LanguageCases, if any
+	DeviceClass    string // "bot", "mobile", or "desktop", set by DeviceClassEnricher
+	Claims         map[string]interface{} // Auth claims, set by JWTClaimsEnricher
+}
+
+// NotAcceptableError is returned by GetResponseForRequest when the client's
+// Accept header explicitly excludes every representation the manifest can
+// produce. Callers should respond with HTTP 406 and may use Available to
+// tell the client what it could have asked for instead.
+type NotAcceptableError struct {
+	Available []string
+}
+
+func (e *NotAcceptableError) Error() string {
+	return fmt.Sprintf("no acceptable representation, available: %s", strings.Join(e.Available, ", "))
 }
 
 // ManifestManager handles the storage and retrieval of service manifests
 type ManifestManager struct {
-	manifests map[string]*ServiceManifest
-	mu        sync.RWMutex
-	basePath  string
+	manifests  map[string]*ServiceManifest
+	mu         sync.RWMutex
+	basePath   string
+	authorizer Authorizer
+	enrichers  []ContextEnricher
+
+	arrayStrategies ArrayStrategyFor
+	signingKeys     *KeyStore
+
+	writeLocksMu sync.Mutex
+	writeLocks   map[string]*sync.Mutex
+}
+
+// lockService returns the mutex serializing admin writes (PUT/PATCH/DELETE)
+// for one service, creating it on first use. Callers must hold it across
+// the whole check-then-write sequence - read the current ETag, validate
+// If-Match/If-None-Match against it, then write - so two concurrent
+// requests with the same precondition can't both pass the check and race
+// to clobber each other's write.
+func (m *ManifestManager) lockService(service string) *sync.Mutex {
+	m.writeLocksMu.Lock()
+	defer m.writeLocksMu.Unlock()
+	if m.writeLocks == nil {
+		m.writeLocks = make(map[string]*sync.Mutex)
+	}
+	l, ok := m.writeLocks[service]
+	if !ok {
+		l = &sync.Mutex{}
+		m.writeLocks[service] = l
+	}
+	return l
+}
+
+// SigningKeys returns the manager's signing key store, creating an empty
+// one on first use.
+func (m *ManifestManager) SigningKeys() *KeyStore {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.signingKeys == nil {
+		m.signingKeys = NewKeyStore()
+	}
+	return m.signingKeys
 }
 
 // NewManifestManager creates a new manifest manager
 func NewManifestManager(basePath string) *ManifestManager {
 	return &ManifestManager{
-		manifests: make(map[string]*ServiceManifest),
-		basePath:  basePath,
+		manifests:  make(map[string]*ServiceManifest),
+		basePath:   basePath,
+		authorizer: AllowAll{},
 	}
 }
 
@@ -58,6 +117,83 @@ func sanitizeFilename(serviceURL string) string {
 	return serviceURL
 }
 
+// pathFor returns the on-disk path for a service's manifest file.
+func (m *ManifestManager) pathFor(serviceURL string) string {
+	return filepath.Join(m.basePath, sanitizeFilename(serviceURL)+".json")
+}
+
+// readManifestRaw reads a manifest's on-disk JSON verbatim, without
+// unmarshaling it, for callers that need the exact bytes (ETags, patching).
+func (m *ManifestManager) readManifestRaw(serviceURL string) ([]byte, error) {
+	return os.ReadFile(m.pathFor(serviceURL))
+}
+
+// writeManifestFile atomically persists raw manifest JSON via write-to-temp
+// plus rename, then invalidates the cached copy under the write lock.
+func (m *ManifestManager) writeManifestFile(serviceURL string, data []byte) error {
+	tmp, err := os.CreateTemp(m.basePath, ".manifest-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once the rename below succeeds
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, m.pathFor(serviceURL)); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	delete(m.manifests, serviceURL)
+	m.mu.Unlock()
+	return nil
+}
+
+// InvalidateCache drops every cached manifest, forcing the next request for
+// each service to reload its JSON from disk and recompile its templates.
+// Callers that land files directly under basePath outside the normal
+// UpdateManifest/DeleteManifest path (e.g. the resumable upload endpoint
+// writing a template file) must call this, since the compiled-template
+// cache inside a *ServiceManifest otherwise lives for the process lifetime.
+func (m *ManifestManager) InvalidateCache() {
+	m.mu.Lock()
+	m.manifests = make(map[string]*ServiceManifest)
+	m.mu.Unlock()
+}
+
+// DeleteManifest removes a manifest from disk and invalidates its cache entry.
+func (m *ManifestManager) DeleteManifest(serviceURL string) error {
+	if err := os.Remove(m.pathFor(serviceURL)); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	delete(m.manifests, serviceURL)
+	m.mu.Unlock()
+	return nil
+}
+
+// ListServices returns the identifiers of every service with a manifest on disk.
+func (m *ManifestManager) ListServices() ([]string, error) {
+	entries, err := os.ReadDir(m.basePath)
+	if err != nil {
+		return nil, err
+	}
+	services := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		services = append(services, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	return services, nil
+}
+
 // LoadManifest loads a manifest for a service URL
 func (m *ManifestManager) LoadManifest(serviceURL string) (*ServiceManifest, error) {
 	// First try to read from cache
@@ -69,8 +205,7 @@ func (m *ManifestManager) LoadManifest(serviceURL string) (*ServiceManifest, err
 	m.mu.RUnlock()
 
 	// If not in cache, load from file
-	manifestPath := filepath.Join(m.basePath, sanitizeFilename(serviceURL)+".json")
-	data, err := os.ReadFile(manifestPath)
+	data, err := m.readManifestRaw(serviceURL)
 	if err != nil {
 		if os.IsNotExist(err) {
 			// Return default manifest if file doesn't exist
@@ -106,27 +241,23 @@ func (m *ManifestManager) LoadManifest(serviceURL string) (*ServiceManifest, err
 	return &manifest, nil
 }
 
-// determineResponseType analyzes Accept headers to choose response type
-func (m *ManifestManager) determineResponseType(accept []string) string {
-	// Default to JSON if no Accept header
-	if len(accept) == 0 {
-		return "application/json"
+// availableContentTypes lists the representations a manifest can actually
+// produce: application/json when a default response is set, plus whatever
+// content types its templates declare.
+func availableContentTypes(manifest *ServiceManifest) []string {
+	var types []string
+	seen := make(map[string]bool)
+	if len(manifest.DefaultResponse) > 0 {
+		types = append(types, "application/json")
+		seen["application/json"] = true
 	}
-
-	// Parse and sort Accept header by q value
-	for _, typ := range accept {
-		if strings.Contains(typ, "text/html") {
-			return "text/html"
-		}
-		if strings.Contains(typ, "text/plain") {
-			return "text/plain"
-		}
-		if strings.Contains(typ, "application/json") {
-			return "application/json"
+	for _, tmpl := range manifest.Templates {
+		if !seen[tmpl.ContentType] {
+			types = append(types, tmpl.ContentType)
+			seen[tmpl.ContentType] = true
 		}
 	}
-
-	return "application/json" // default fallback
+	return types
 }
 
 // urlize converts a string to a URL-friendly format
@@ -137,32 +268,62 @@ func urlize(s string) string {
 	return s
 }
 
-// GetResponseForRequest gets the appropriate response for a given request context
-func (m *ManifestManager) GetResponseForRequest(serviceURL string, ctx *RequestContext) (interface{}, string, error) {
+// GetResponseForRequest gets the appropriate response for a given request
+// context. The fourth return value is a detached JWS over the response body
+// (see KeyStore.Sign), set only when the manifest declares a signing_key_id;
+// callers should publish it as X-Manifest-Signature.
+func (m *ManifestManager) GetResponseForRequest(serviceURL string, ctx *RequestContext) (interface{}, string, string, error) {
 	fmt.Printf("Loading manifest for: %s\n", serviceURL)
 	manifest, err := m.LoadManifest(serviceURL)
 	if err != nil {
 		fmt.Printf("Error loading manifest: %v\n", err)
-		return nil, "", err
+		return nil, "", "", err
 	}
 
-	// Determine content type
-	responseType := m.determineResponseType(ctx.AcceptTypes)
+	// Determine content type via RFC 7231 content negotiation
+	available := availableContentTypes(manifest)
+	responseType, ok := negotiateContentType(ctx.AcceptTypes, available)
+	if !ok {
+		fmt.Printf("No acceptable representation for Accept: %v (available: %v)\n", ctx.AcceptTypes, available)
+		return nil, "", "", &NotAcceptableError{Available: available}
+	}
 	ctx.PreferredType = responseType
 	fmt.Printf("Response type determined as: %s\n", responseType)
 
+	// Negotiate a language override, if the manifest has any
+	if len(manifest.LanguageCases) > 0 {
+		if lang, ok := negotiateLanguage(ctx.AcceptLanguage, keysOf(manifest.LanguageCases)); ok {
+			ctx.Language = lang
+			fmt.Printf("Language determined as: %s\n", lang)
+		}
+	}
+
 	// Get raw JSON response based on user agent and country
 	fmt.Printf("Matching request context for user agent: %s\n", ctx.UserAgent)
 	rawResponse, err := m.matchRequestContext(manifest, ctx)
 	if err != nil {
 		fmt.Printf("Error matching request context: %v\n", err)
-		return nil, "", err
+		return nil, "", "", err
+	}
+
+	// sign computes the detached signature for a finished response body, if
+	// the manifest asked for one.
+	sign := func(body []byte) string {
+		if manifest.SigningKeyID == "" {
+			return ""
+		}
+		jws, err := m.SigningKeys().Sign(manifest.SigningKeyID, body)
+		if err != nil {
+			fmt.Printf("⚠️ Failed to sign response with key %q: %v\n", manifest.SigningKeyID, err)
+			return ""
+		}
+		return jws
 	}
 
 	// If JSON is requested, return as is
 	if responseType == "application/json" {
 		fmt.Printf("Returning JSON response\n")
-		return rawResponse, responseType, nil
+		return string(rawResponse), responseType, sign(rawResponse), nil
 	}
 
 	// For other types, find and apply appropriate template
@@ -176,9 +337,14 @@ func (m *ManifestManager) GetResponseForRequest(serviceURL string, ctx *RequestC
 
 			if tmplCopy.compiled == nil {
 				fmt.Printf("Template not compiled, compiling now\n")
-				// Create template with custom functions
+				// Create template with custom functions. "claim" is a
+				// placeholder here so the parser accepts it; the real,
+				// request-scoped implementation is bound in at execute time
+				// below since compiled templates are cached and shared
+				// across requests.
 				funcMap := template.FuncMap{
 					"urlize": urlize,
+					"claim":  func(string) interface{} { return nil },
 				}
 
 				// Get template content
@@ -190,7 +356,7 @@ func (m *ManifestManager) GetResponseForRequest(serviceURL string, ctx *RequestC
 					content, err := os.ReadFile(templatePath)
 					if err != nil {
 						fmt.Printf("Error reading template file: %v\n", err)
-						return nil, "", fmt.Errorf("template file error: %v", err)
+						return nil, "", "", fmt.Errorf("template file error: %v", err)
 					}
 					templateContent = string(content)
 				} else {
@@ -202,7 +368,7 @@ func (m *ManifestManager) GetResponseForRequest(serviceURL string, ctx *RequestC
 				compiled, err := template.New("response").Funcs(funcMap).Parse(templateContent)
 				if err != nil {
 					fmt.Printf("Error compiling template: %v\n", err)
-					return nil, "", fmt.Errorf("template compilation error: %v", err)
+					return nil, "", "", fmt.Errorf("template compilation error: %v", err)
 				}
 
 				// Store compiled template in the manifest with proper locking
@@ -218,23 +384,40 @@ func (m *ManifestManager) GetResponseForRequest(serviceURL string, ctx *RequestC
 			var data interface{}
 			if err := json.Unmarshal(rawResponse, &data); err != nil {
 				fmt.Printf("Error unmarshaling JSON: %v\n", err)
-				return nil, "", err
+				return nil, "", "", err
 			}
 
+			// Clone before rebinding "claim" so this request's claims don't
+			// leak into the cached template other requests share.
+			execTemplate, err := tmplCopy.compiled.Clone()
+			if err != nil {
+				fmt.Printf("Error cloning template: %v\n", err)
+				return nil, "", "", err
+			}
+			execTemplate = execTemplate.Funcs(template.FuncMap{
+				"claim": func(key string) interface{} {
+					if ctx.Claims == nil {
+						return nil
+					}
+					return ctx.Claims[key]
+				},
+			})
+
 			var buf strings.Builder
-			if err := tmplCopy.compiled.Execute(&buf, data); err != nil {
+			if err := execTemplate.Execute(&buf, data); err != nil {
 				fmt.Printf("Error executing template: %v\n", err)
-				return nil, "", err
+				return nil, "", "", err
 			}
 
 			fmt.Printf("Template execution successful\n")
-			return buf.String(), responseType, nil
+			body := buf.String()
+			return body, responseType, sign([]byte(body)), nil
 		}
 	}
 
 	fmt.Printf("No matching template found, returning plain text %s\n", manifest.Templates)
 	// If no template found, convert to string representation
-	return string(rawResponse), "text/plain", nil
+	return string(rawResponse), "text/plain", sign(rawResponse), nil
 }
 
 // matchRequestContext matches a request context against manifest cases and merges responses
@@ -254,12 +437,35 @@ func (m *ManifestManager) matchRequestContext(manifest *ServiceManifest, ctx *Re
 		}
 	}
 
-	// If user agent is specified, try to merge user agent specific response
+	// If a language was negotiated, try to merge its localized response
+	if ctx.Language != "" {
+		if langResponse, exists := manifest.LanguageCases[ctx.Language]; exists {
+			merged, err := m.mergeResponses(response, langResponse)
+			if err != nil {
+				return nil, err
+			}
+			response = merged
+		}
+	}
+
+	// If a device class was determined, try to merge its specific response
+	if ctx.DeviceClass != "" {
+		if deviceResponse, exists := manifest.DeviceCases[ctx.DeviceClass]; exists {
+			merged, err := m.mergeResponses(response, deviceResponse)
+			if err != nil {
+				return nil, err
+			}
+			response = merged
+		}
+	}
+
+	// If user agent is specified, try to merge user agent specific response.
+	// Patterns are tried longest-first so matching is deterministic instead
+	// of dependent on map iteration order.
 	if ctx.UserAgent != "" {
-		for uaPattern, uaResponse := range manifest.UserAgentCases {
-			if strings.Contains(ctx.UserAgent, uaPattern) {
-				// Merge user agent response with current response
-				merged, err := m.mergeResponses(response, uaResponse)
+		for _, uaPattern := range sortedUAPatterns(manifest.UserAgentCases) {
+			if uaPatternMatches(uaPattern, ctx.UserAgent) {
+				merged, err := m.mergeResponses(response, manifest.UserAgentCases[uaPattern])
 				if err != nil {
 					return nil, err
 				}
@@ -272,24 +478,35 @@ func (m *ManifestManager) matchRequestContext(manifest *ServiceManifest, ctx *Re
 	return response, nil
 }
 
-// mergeResponses merges two JSON responses, with the second response taking precedence
+// mergeResponses combines two JSON responses. If overrideResp decodes to an
+// RFC 6902 JSON Patch document (a JSON array of {op,path,...} objects), its
+// operations are applied against defaultResp. Otherwise overrideResp is
+// deep-merged into defaultResp: objects merge recursively key by key, and
+// arrays are combined per m.arrayStrategies (default: the override array
+// wins outright, per ArrayReplace).
 func (m *ManifestManager) mergeResponses(defaultResp, overrideResp json.RawMessage) (json.RawMessage, error) {
-	var defaultMap, overrideMap map[string]interface{}
-
-	if err := json.Unmarshal(defaultResp, &defaultMap); err != nil {
+	var overrideVal interface{}
+	if err := json.Unmarshal(overrideResp, &overrideVal); err != nil {
 		return nil, err
 	}
-	if err := json.Unmarshal(overrideResp, &overrideMap); err != nil {
-		return nil, err
+
+	var baseVal interface{}
+	if len(defaultResp) > 0 {
+		if err := json.Unmarshal(defaultResp, &baseVal); err != nil {
+			return nil, err
+		}
 	}
 
-	// Merge maps
-	for k, v := range overrideMap {
-		defaultMap[k] = v
+	if ops, ok := asJSONPatch(overrideVal); ok {
+		patched, err := applyJSONPatch(baseVal, ops)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(patched)
 	}
 
-	// Convert back to JSON
-	return json.Marshal(defaultMap)
+	merged := deepMerge(baseVal, overrideVal, "", m.arrayStrategySnapshot())
+	return json.Marshal(merged)
 }
 
 // GetResponseForUserAgent gets the appropriate response for a given user agent
@@ -315,22 +532,20 @@ func keysOf(m map[string]json.RawMessage) []string {
 	return keys
 }
 
-// UpdateManifest updates the manifest for a service URL
+// UpdateManifest updates the manifest for a service URL, persisting it
+// atomically and refreshing the cache.
 func (m *ManifestManager) UpdateManifest(serviceURL string, manifest *ServiceManifest) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	// Save to file
-	manifestPath := filepath.Join(m.basePath, sanitizeFilename(serviceURL)+".json")
 	data, err := json.MarshalIndent(manifest, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	if err := os.WriteFile(manifestPath, data, 0644); err != nil {
+	if err := m.writeManifestFile(serviceURL, data); err != nil {
 		return err
 	}
 
+	m.mu.Lock()
 	m.manifests[serviceURL] = manifest
+	m.mu.Unlock()
 	return nil
 }
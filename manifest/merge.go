@@ -0,0 +1,203 @@
+package manifest
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ArrayStrategy controls how a JSON array is combined during a deep merge.
+type ArrayStrategy int
+
+const (
+	// ArrayReplace (the default) makes the override array win outright.
+	ArrayReplace ArrayStrategy = iota
+	// ArrayAppend concatenates the override array onto the base array.
+	ArrayAppend
+	// ArrayMergeByID matches base and override elements on a shared "id"
+	// field, deep-merging matches and appending anything unmatched.
+	ArrayMergeByID
+)
+
+// ArrayStrategyFor maps a dot-delimited key path (e.g. "nav.items") to the
+// ArrayStrategy used when a deep merge encounters an array there. A path
+// with no entry defaults to ArrayReplace.
+type ArrayStrategyFor map[string]ArrayStrategy
+
+// SetArrayStrategy configures the merge strategy for arrays found at
+// keyPath. Call it once per path of interest before manifests are served.
+func (m *ManifestManager) SetArrayStrategy(keyPath string, strategy ArrayStrategy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.arrayStrategies == nil {
+		m.arrayStrategies = make(ArrayStrategyFor)
+	}
+	m.arrayStrategies[keyPath] = strategy
+}
+
+func (m *ManifestManager) arrayStrategySnapshot() ArrayStrategyFor {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	snapshot := make(ArrayStrategyFor, len(m.arrayStrategies))
+	for k, v := range m.arrayStrategies {
+		snapshot[k] = v
+	}
+	return snapshot
+}
+
+// deepMerge recursively merges override into base: objects merge key by
+// key (recursing into shared keys), arrays combine per strategies[path],
+// and any other value type is simply replaced by override.
+func deepMerge(base, override interface{}, path string, strategies ArrayStrategyFor) interface{} {
+	switch overrideVal := override.(type) {
+	case map[string]interface{}:
+		baseMap, _ := base.(map[string]interface{})
+		merged := make(map[string]interface{}, len(baseMap)+len(overrideVal))
+		for k, v := range baseMap {
+			merged[k] = v
+		}
+		for k, v := range overrideVal {
+			merged[k] = deepMerge(merged[k], v, joinPath(path, k), strategies)
+		}
+		return merged
+	case []interface{}:
+		baseSlice, _ := base.([]interface{})
+		return mergeArrays(baseSlice, overrideVal, strategies[path])
+	default:
+		return override
+	}
+}
+
+func joinPath(base, key string) string {
+	if base == "" {
+		return key
+	}
+	return base + "." + key
+}
+
+func mergeArrays(base, override []interface{}, strategy ArrayStrategy) []interface{} {
+	switch strategy {
+	case ArrayAppend:
+		merged := make([]interface{}, 0, len(base)+len(override))
+		merged = append(merged, base...)
+		merged = append(merged, override...)
+		return merged
+	case ArrayMergeByID:
+		return mergeArraysByID(base, override)
+	default: // ArrayReplace
+		return override
+	}
+}
+
+// mergeArraysByID matches elements by a shared "id" field, deep-merging
+// matches in place and appending anything from override that doesn't match
+// (including elements with no "id" at all).
+func mergeArraysByID(base, override []interface{}) []interface{} {
+	result := make([]interface{}, len(base))
+	copy(result, base)
+
+	indexByID := make(map[interface{}]int, len(base))
+	for i, item := range result {
+		if obj, ok := item.(map[string]interface{}); ok {
+			if id, ok := obj["id"]; ok {
+				indexByID[id] = i
+			}
+		}
+	}
+
+	for _, item := range override {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			result = append(result, item)
+			continue
+		}
+		id, hasID := obj["id"]
+		if !hasID {
+			result = append(result, item)
+			continue
+		}
+		i, exists := indexByID[id]
+		if !exists {
+			indexByID[id] = len(result)
+			result = append(result, item)
+			continue
+		}
+		result[i] = deepMerge(result[i], obj, "", nil)
+	}
+	return result
+}
+
+// uaPatternMatches tests a UserAgentCases key against a User-Agent string.
+// A "re:" prefix means the rest of the pattern is a regexp; a pattern
+// containing glob metacharacters (*, ?, [) is matched as a glob against the
+// whole UA string (unlike path.Match, "*" here matches "/" too, since real
+// UA strings are full of slashes, e.g. "Chrome/91.0.4472.124"); anything
+// else falls back to the original substring-contains behavior.
+func uaPatternMatches(pattern, userAgent string) bool {
+	if rx, ok := strings.CutPrefix(pattern, "re:"); ok {
+		re, err := regexp.Compile(rx)
+		if err != nil {
+			return false
+		}
+		return re.MatchString(userAgent)
+	}
+	if strings.ContainsAny(pattern, "*?[") {
+		re, err := globToRegexp(pattern)
+		if err != nil {
+			return false
+		}
+		return re.MatchString(userAgent)
+	}
+	return strings.Contains(userAgent, pattern)
+}
+
+// globToRegexp translates a shell-style glob ("*", "?", "[...]") into an
+// anchored regexp. Unlike path.Match/filepath.Match, "*" matches "/" too,
+// since it's applied to whole strings (User-Agents) rather than paths.
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end == -1 {
+				b.WriteString(regexp.QuoteMeta(pattern[i:]))
+				i = len(pattern)
+				continue
+			}
+			class := pattern[i : i+end+1]
+			if strings.HasPrefix(class, "[!") {
+				class = "[^" + class[2:]
+			}
+			b.WriteString(class)
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// sortedUAPatterns orders UserAgentCases keys by specificity, longest
+// pattern first (equal lengths in lexical order), so matching is
+// deterministic instead of map-order-dependent.
+func sortedUAPatterns(cases map[string]json.RawMessage) []string {
+	patterns := make([]string, 0, len(cases))
+	for k := range cases {
+		patterns = append(patterns, k)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+	return patterns
+}
@@ -0,0 +1,184 @@
+package manifest
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func mustRaw(t *testing.T, v string) json.RawMessage {
+	t.Helper()
+	return json.RawMessage(v)
+}
+
+func TestMergeResponsesDeepMerge(t *testing.T) {
+	m := NewManifestManager(t.TempDir())
+
+	base := mustRaw(t, `{"nav":{"title":"Home","items":["a","b"]},"footer":"base"}`)
+	override := mustRaw(t, `{"nav":{"title":"Override"}}`)
+
+	merged, err := m.mergeResponses(base, override)
+	if err != nil {
+		t.Fatalf("mergeResponses: %v", err)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(merged, &result); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+
+	nav := result["nav"].(map[string]interface{})
+	if nav["title"] != "Override" {
+		t.Errorf("nav.title = %v, want Override", nav["title"])
+	}
+	items := nav["items"].([]interface{})
+	if len(items) != 2 || items[0] != "a" {
+		t.Errorf("nav.items = %v, want the base slice to survive an untouched nested merge", items)
+	}
+	if result["footer"] != "base" {
+		t.Errorf("footer = %v, want base (untouched key preserved)", result["footer"])
+	}
+}
+
+func TestMergeResponsesArrayStrategies(t *testing.T) {
+	base := mustRaw(t, `{"links":[{"id":"1","label":"Old"},{"id":"2","label":"Keep"}]}`)
+	override := mustRaw(t, `{"links":[{"id":"1","label":"New"},{"id":"3","label":"Added"}]}`)
+
+	t.Run("replace (default)", func(t *testing.T) {
+		m := NewManifestManager(t.TempDir())
+		merged, err := m.mergeResponses(base, override)
+		if err != nil {
+			t.Fatalf("mergeResponses: %v", err)
+		}
+		var result map[string]interface{}
+		json.Unmarshal(merged, &result)
+		links := result["links"].([]interface{})
+		if len(links) != 2 {
+			t.Fatalf("len(links) = %d, want 2 (override wins outright)", len(links))
+		}
+	})
+
+	t.Run("append", func(t *testing.T) {
+		m := NewManifestManager(t.TempDir())
+		m.SetArrayStrategy("links", ArrayAppend)
+		merged, err := m.mergeResponses(base, override)
+		if err != nil {
+			t.Fatalf("mergeResponses: %v", err)
+		}
+		var result map[string]interface{}
+		json.Unmarshal(merged, &result)
+		links := result["links"].([]interface{})
+		if len(links) != 4 {
+			t.Fatalf("len(links) = %d, want 4 (base ++ override)", len(links))
+		}
+	})
+
+	t.Run("merge-by-id", func(t *testing.T) {
+		m := NewManifestManager(t.TempDir())
+		m.SetArrayStrategy("links", ArrayMergeByID)
+		merged, err := m.mergeResponses(base, override)
+		if err != nil {
+			t.Fatalf("mergeResponses: %v", err)
+		}
+		var result map[string]interface{}
+		json.Unmarshal(merged, &result)
+		links := result["links"].([]interface{})
+		if len(links) != 3 {
+			t.Fatalf("len(links) = %d, want 3 (id 1 merged, id 2 kept, id 3 added)", len(links))
+		}
+		byID := make(map[string]string)
+		for _, l := range links {
+			obj := l.(map[string]interface{})
+			byID[obj["id"].(string)] = obj["label"].(string)
+		}
+		if byID["1"] != "New" || byID["2"] != "Keep" || byID["3"] != "Added" {
+			t.Errorf("unexpected merge result: %v", byID)
+		}
+	})
+}
+
+func TestMergeResponsesJSONPatch(t *testing.T) {
+	m := NewManifestManager(t.TempDir())
+
+	base := mustRaw(t, `{"message":"hello","tags":["a","b"]}`)
+	patch := mustRaw(t, `[
+		{"op":"replace","path":"/message","value":"patched"},
+		{"op":"add","path":"/tags/-","value":"c"},
+		{"op":"remove","path":"/tags/0"}
+	]`)
+
+	merged, err := m.mergeResponses(base, patch)
+	if err != nil {
+		t.Fatalf("mergeResponses: %v", err)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(merged, &result); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if result["message"] != "patched" {
+		t.Errorf("message = %v, want patched", result["message"])
+	}
+	tags := result["tags"].([]interface{})
+	if len(tags) != 2 || tags[0] != "b" || tags[1] != "c" {
+		t.Errorf("tags = %v, want [b c]", tags)
+	}
+}
+
+func TestUAPatternMatchesGlob(t *testing.T) {
+	chromeUA := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"
+
+	cases := []struct {
+		name, pattern, ua string
+		want              bool
+	}{
+		{"star crosses slashes in a real UA", "*Chrome*", chromeUA, true},
+		{"star crosses slashes with a version prefix", "*Chrome/91*", chromeUA, true},
+		{"non-matching glob", "*Firefox*", chromeUA, false},
+		{"question mark matches a single char", "Chrome/91.0.4472.12?", "Chrome/91.0.4472.124", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := uaPatternMatches(c.pattern, c.ua); got != c.want {
+				t.Errorf("uaPatternMatches(%q, %q) = %v, want %v", c.pattern, c.ua, got, c.want)
+			}
+		})
+	}
+}
+
+func TestMatchRequestContextUserAgentPrecedence(t *testing.T) {
+	manifest := &ServiceManifest{
+		DefaultResponse: mustRaw(t, `{"theme":"default"}`),
+		UserAgentCases: map[string]json.RawMessage{
+			"Mozilla":             mustRaw(t, `{"theme":"generic-browser"}`),
+			"Mozilla/5.0 (iPhone": mustRaw(t, `{"theme":"iphone"}`),
+			"re:(?i)bot":          mustRaw(t, `{"theme":"bot"}`),
+		},
+	}
+	m := NewManifestManager(t.TempDir())
+
+	cases := []struct {
+		name, ua, wantTheme string
+	}{
+		{"longest literal wins over shorter substring", "Mozilla/5.0 (iPhone; CPU OS)", "iphone"},
+		{"shorter literal still matches when longest doesn't apply", "Mozilla/5.0 (Windows NT)", "generic-browser"},
+		{"regex pattern matches", "Some-Crawler-Bot/1.0", "bot"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := &RequestContext{UserAgent: c.ua}
+			raw, err := m.matchRequestContext(manifest, ctx)
+			if err != nil {
+				t.Fatalf("matchRequestContext: %v", err)
+			}
+			var result map[string]interface{}
+			if err := json.Unmarshal(raw, &result); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if result["theme"] != c.wantTheme {
+				t.Errorf("theme = %v, want %v", result["theme"], c.wantTheme)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package manifest
+
+import "encoding/json"
+
+// applyMergePatch applies an RFC 7396 JSON Merge Patch to target, returning
+// the merged document. A null value in the patch removes the corresponding
+// key; a non-object patch replaces target wholesale.
+func applyMergePatch(target, patch json.RawMessage) (json.RawMessage, error) {
+	var patchVal interface{}
+	if len(patch) == 0 {
+		patchVal = nil
+	} else if err := json.Unmarshal(patch, &patchVal); err != nil {
+		return nil, err
+	}
+
+	patchObj, ok := patchVal.(map[string]interface{})
+	if !ok {
+		// The patch is not a JSON object, so per RFC 7396 it replaces the target.
+		if patch == nil {
+			return nil, nil
+		}
+		return patch, nil
+	}
+
+	var targetObj map[string]interface{}
+	if len(target) > 0 {
+		var targetVal interface{}
+		if err := json.Unmarshal(target, &targetVal); err != nil {
+			return nil, err
+		}
+		targetObj, _ = targetVal.(map[string]interface{})
+	}
+	if targetObj == nil {
+		targetObj = make(map[string]interface{})
+	}
+
+	merged := mergePatchObject(targetObj, patchObj)
+	return json.Marshal(merged)
+}
+
+func mergePatchObject(target, patch map[string]interface{}) map[string]interface{} {
+	for k, v := range patch {
+		if v == nil {
+			delete(target, k)
+			continue
+		}
+		if patchChild, ok := v.(map[string]interface{}); ok {
+			targetChild, ok := target[k].(map[string]interface{})
+			if !ok {
+				targetChild = make(map[string]interface{})
+			}
+			target[k] = mergePatchObject(targetChild, patchChild)
+			continue
+		}
+		target[k] = v
+	}
+	return target
+}
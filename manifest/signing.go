@@ -0,0 +1,130 @@
+package manifest
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SigningKey is a named Ed25519 keypair used to sign manifest responses.
+type SigningKey struct {
+	ID         string
+	PrivateKey ed25519.PrivateKey
+}
+
+// KeyStore holds the signing keys a ManifestManager can sign responses with,
+// keyed by the signing_key_id a manifest declares.
+type KeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]SigningKey
+}
+
+// NewKeyStore creates an empty KeyStore.
+func NewKeyStore() *KeyStore {
+	return &KeyStore{keys: make(map[string]SigningKey)}
+}
+
+// Add registers a key under id, replacing any existing key with that id.
+func (ks *KeyStore) Add(id string, priv ed25519.PrivateKey) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys[id] = SigningKey{ID: id, PrivateKey: priv}
+}
+
+// Get looks up a key by id.
+func (ks *KeyStore) Get(id string) (SigningKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	k, ok := ks.keys[id]
+	return k, ok
+}
+
+// LoadKeysFromDir loads one Ed25519 key per file in dir. Each file holds a
+// raw 32-byte seed; the filename without its extension becomes the key ID -
+// e.g. "manifest/keys/prod.seed" registers key ID "prod".
+func (ks *KeyStore) LoadKeysFromDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		seed, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		if len(seed) != ed25519.SeedSize {
+			return fmt.Errorf("signing key %s: expected a %d-byte seed, got %d", entry.Name(), ed25519.SeedSize, len(seed))
+		}
+		id := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		ks.Add(id, ed25519.NewKeyFromSeed(seed))
+	}
+	return nil
+}
+
+// JWKS returns the public half of every key as a JSON Web Key Set (RFC
+// 7517), suitable for publishing at /.well-known/jwks.json.
+func (ks *KeyStore) JWKS() json.RawMessage {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	type jwk struct {
+		Kty string `json:"kty"`
+		Crv string `json:"crv"`
+		X   string `json:"x"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Alg string `json:"alg"`
+	}
+
+	keys := make([]jwk, 0, len(ks.keys))
+	for id, k := range ks.keys {
+		pub := k.PrivateKey.Public().(ed25519.PublicKey)
+		keys = append(keys, jwk{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+			Kid: id,
+			Use: "sig",
+			Alg: "EdDSA",
+		})
+	}
+
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		return json.RawMessage(`{"keys":[]}`)
+	}
+	return data
+}
+
+// Sign produces a detached JWS over body (RFC 7797 unencoded-payload style):
+// the compact serialization "<header>..<signature>", with the payload
+// segment left empty since the caller already has body in hand.
+func (ks *KeyStore) Sign(keyID string, body []byte) (string, error) {
+	key, ok := ks.Get(keyID)
+	if !ok {
+		return "", fmt.Errorf("unknown signing key %q", keyID)
+	}
+
+	header := fmt.Sprintf(`{"alg":"EdDSA","kid":%q,"b64":false,"crit":["b64"]}`, keyID)
+	encodedHeader := base64.RawURLEncoding.EncodeToString([]byte(header))
+
+	signingInput := make([]byte, 0, len(encodedHeader)+1+len(body))
+	signingInput = append(signingInput, encodedHeader...)
+	signingInput = append(signingInput, '.')
+	signingInput = append(signingInput, body...)
+
+	signature := ed25519.Sign(key.PrivateKey, signingInput)
+	return encodedHeader + ".." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
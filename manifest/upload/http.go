@@ -0,0 +1,141 @@
+package upload
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Authorizer decides whether an upload request may proceed. Install a real
+// implementation via Manager.Authorizer before exposing the upload endpoint
+// publicly - a destination like "manifest" lets a caller overwrite any
+// manifest or template file on disk. Manager.Authorizer is nil by default,
+// and nil means every request is allowed.
+type Authorizer interface {
+	Authorize(r *http.Request) error
+}
+
+// Handler serves the resumable upload API. Mount it with http.StripPrefix so
+// that r.URL.Path holds only the session ID (or is empty, for POST to start
+// a new session); prefix is the externally visible mount point, used to
+// build Location headers.
+//
+//	POST  /                       start a session; 202 with Location, Range: 0-0
+//	PATCH /<id>                   append a byte range; 202 with updated Range
+//	PUT   /<id>?digest=&dest=&path=  finalize; 201 with the final path
+func (m *Manager) Handler(prefix string) http.Handler {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.Authorizer != nil {
+			if err := m.Authorizer.Authorize(r); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+		}
+
+		id := strings.Trim(r.URL.Path, "/")
+
+		switch {
+		case r.Method == http.MethodPost && id == "":
+			m.handleStart(w, prefix)
+		case r.Method == http.MethodPatch && id != "":
+			m.handlePatch(w, r, id, prefix)
+		case r.Method == http.MethodPut && id != "":
+			m.handlePut(w, r, id)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func (m *Manager) handleStart(w http.ResponseWriter, prefix string) {
+	s, err := m.StartSession()
+	if err != nil {
+		http.Error(w, "failed to start upload", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Location", prefix+"/"+s.ID)
+	w.Header().Set("Range", "0-0")
+	w.Header().Set("Docker-Upload-UUID", s.ID)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (m *Manager) handlePatch(w http.ResponseWriter, r *http.Request, id, prefix string) {
+	s, ok := m.session(id)
+	if !ok {
+		http.Error(w, "unknown upload session", http.StatusNotFound)
+		return
+	}
+
+	start := s.currentOffset()
+	if cr := r.Header.Get("Content-Range"); cr != "" {
+		if parsed, ok := parseRangeStart(cr); ok {
+			start = parsed
+		}
+	}
+
+	total, err := s.AppendChunk(start, r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	w.Header().Set("Location", prefix+"/"+id)
+	w.Header().Set("Range", fmt.Sprintf("0-%d", total-1))
+	w.Header().Set("Docker-Upload-UUID", id)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (m *Manager) handlePut(w http.ResponseWriter, r *http.Request, id string) {
+	s, ok := m.session(id)
+	if !ok {
+		http.Error(w, "unknown upload session", http.StatusNotFound)
+		return
+	}
+
+	if r.ContentLength > 0 {
+		if _, err := s.AppendChunk(s.currentOffset(), r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	digest := r.URL.Query().Get("digest")
+	path := r.URL.Query().Get("path")
+	dest := r.URL.Query().Get("dest")
+	if dest == "" {
+		dest = "manifest"
+	}
+	if digest == "" || path == "" {
+		http.Error(w, "digest and path query parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	finalPath, err := m.Finalize(id, digest, dest, path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"path": finalPath})
+}
+
+// parseRangeStart parses the start offset from a "Content-Range: bytes N-M/*"
+// (or bare "N-M") header value.
+func parseRangeStart(headerVal string) (int64, bool) {
+	v := strings.TrimPrefix(headerVal, "bytes ")
+	v, _, _ = strings.Cut(v, "/")
+	startStr, _, ok := strings.Cut(v, "-")
+	if !ok {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(startStr), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return start, true
+}
@@ -0,0 +1,240 @@
+// Package upload implements a Docker-distribution-style resumable upload
+// flow: start a session, PATCH byte ranges into it, then finalize once the
+// whole file's digest checks out.
+package upload
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Session tracks the state of one in-progress resumable upload.
+type Session struct {
+	ID           string
+	tmpPath      string
+	received     int64
+	lastActivity time.Time
+	mu           sync.Mutex
+}
+
+func (s *Session) currentOffset() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.received
+}
+
+// AppendChunk writes data at the given start offset and returns the new
+// total size received. start must match what's already been written -
+// resumable uploads are sequential, not sparse.
+func (s *Session) AppendChunk(start int64, data io.Reader) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if start != s.received {
+		return s.received, fmt.Errorf("range start %d does not match %d bytes already received", start, s.received)
+	}
+
+	f, err := os.OpenFile(s.tmpPath, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return s.received, err
+	}
+	defer f.Close()
+
+	n, err := io.Copy(f, data)
+	s.received += n
+	s.lastActivity = time.Now()
+	if err != nil {
+		return s.received, err
+	}
+	return s.received, nil
+}
+
+// Manager owns in-progress upload sessions plus the temp storage they live
+// in, and the named destination roots a finalized upload may land in.
+type Manager struct {
+	mu           sync.Mutex
+	sessions     map[string]*Session
+	tmpDir       string
+	destinations map[string]string
+	idleTimeout  time.Duration
+	stop         chan struct{}
+
+	// OnFinalize, if set, is called after an upload is successfully moved
+	// into its destination, with the destName ("manifest", "static", ...)
+	// and the final on-disk path. Callers that cache content read from a
+	// destination (e.g. compiled manifest templates) can use this to
+	// invalidate that cache.
+	OnFinalize func(destName, finalPath string)
+
+	// Authorizer, if set, is consulted before every request the Handler
+	// serves. Install a real implementation before exposing the upload
+	// endpoint publicly - see the Authorizer doc comment in http.go.
+	Authorizer Authorizer
+}
+
+// NewManager creates an upload manager. tmpDir holds in-progress files;
+// destinations maps a logical name (used as the "dest" query param on the
+// finalizing PUT) to the directory a completed upload is moved into, e.g.
+// {"manifest": "manifest", "static": "static-overlay"}. A background janitor
+// reaps sessions idle longer than idleTimeout.
+func NewManager(tmpDir string, destinations map[string]string, idleTimeout time.Duration) (*Manager, error) {
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return nil, err
+	}
+	m := &Manager{
+		sessions:     make(map[string]*Session),
+		tmpDir:       tmpDir,
+		destinations: destinations,
+		idleTimeout:  idleTimeout,
+		stop:         make(chan struct{}),
+	}
+	go m.janitor()
+	return m, nil
+}
+
+// Close stops the idle-session janitor.
+func (m *Manager) Close() {
+	close(m.stop)
+}
+
+func (m *Manager) janitor() {
+	interval := m.idleTimeout / 2
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.reapIdle()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+func (m *Manager) reapIdle() {
+	cutoff := time.Now().Add(-m.idleTimeout)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, s := range m.sessions {
+		s.mu.Lock()
+		idle := s.lastActivity.Before(cutoff)
+		path := s.tmpPath
+		s.mu.Unlock()
+		if idle {
+			os.Remove(path)
+			delete(m.sessions, id)
+		}
+	}
+}
+
+// newSessionID generates a UUIDv4-shaped identifier, matching the style of
+// Docker's Docker-Upload-UUID header.
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// StartSession begins a new resumable upload.
+func (m *Manager) StartSession() (*Session, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+	tmpPath := filepath.Join(m.tmpDir, id+".part")
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+
+	s := &Session{ID: id, tmpPath: tmpPath, lastActivity: time.Now()}
+	m.mu.Lock()
+	m.sessions[id] = s
+	m.mu.Unlock()
+	return s, nil
+}
+
+func (m *Manager) session(id string) (*Session, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	return s, ok
+}
+
+// Finalize verifies the assembled upload against digest ("sha256:<hex>") and
+// moves it into destinations[destName]/relPath, creating parent directories
+// as needed.
+func (m *Manager) Finalize(id, digest, destName, relPath string) (string, error) {
+	s, ok := m.session(id)
+	if !ok {
+		return "", fmt.Errorf("unknown upload session %q", id)
+	}
+	destRoot, ok := m.destinations[destName]
+	if !ok {
+		return "", fmt.Errorf("unknown upload destination %q", destName)
+	}
+
+	sum, err := sha256File(s.tmpPath)
+	if err != nil {
+		return "", err
+	}
+	algo, wantHex, ok := strings.Cut(digest, ":")
+	if !ok || algo != "sha256" {
+		return "", fmt.Errorf("unsupported digest %q, want sha256:<hex>", digest)
+	}
+	if !strings.EqualFold(sum, wantHex) {
+		return "", fmt.Errorf("digest mismatch: computed sha256:%s", sum)
+	}
+
+	finalPath := filepath.Join(destRoot, filepath.Clean(string(filepath.Separator)+relPath))
+	if !strings.HasPrefix(finalPath, filepath.Clean(destRoot)+string(filepath.Separator)) {
+		return "", fmt.Errorf("upload path escapes destination root")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(finalPath), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(s.tmpPath, finalPath); err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	delete(m.sessions, id)
+	m.mu.Unlock()
+
+	if m.OnFinalize != nil {
+		m.OnFinalize(destName, finalPath)
+	}
+
+	return finalPath, nil
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}